@@ -20,6 +20,7 @@ package cliparser
 
 import (
 	"errors"
+	"os"
 
 	"github.com/Appliscale/perun/logger"
 	"github.com/Appliscale/perun/utilities"
@@ -38,10 +39,18 @@ var SetupSinkMode = "setup-remote-sink"
 var DestroySinkMode = "destroy-remote-sink"
 var CreateParametersMode = "create-parameters"
 var SetStackPolicyMode = "set-stack-policy"
+var WriteCredentialsMode = "write-credentials"
+var ConfigMode = "config"
+var ConfigMigrateMode = "config-migrate"
+var SpecMode = "spec"
+var SpecUpdateMode = "spec-update"
 
 const JSON = "json"
 const YAML = "yaml"
 
+const TextLogFormat = "text"
+const JSONLogFormat = "json"
+
 type CliArguments struct {
 	Mode                    *string
 	TemplatePath            *string
@@ -56,6 +65,7 @@ type CliArguments struct {
 	Profile                 *string
 	Region                  *string
 	Sandbox                 *bool
+	Partition               *string
 	Stack                   *string
 	Capabilities            *[]string
 	PrettyPrint             *bool
@@ -65,6 +75,30 @@ type CliArguments struct {
 	Unblock                 *bool
 	DisableStackTermination *bool
 	EnableStackTermination  *bool
+	ConfigurationOutput     *string
+	DecisionForMFA          *bool
+	ConfigurationFromFile   *string
+	TargetProfile           *string
+	CredentialsFile         *string
+	SourceProfile           *string
+	RoleArn                 *string
+	CheckCredentials        *bool
+	ConfigMigrateFile       *string
+	SpecUpdateRegion        *string
+	SpecUpdateAll           *bool
+	LogFormat               *string
+	LogFile                 *string
+}
+
+// defaultCredentialsFile resolves the default --credentials-file value,
+// falling back to a relative path if the home directory is unknown.
+func defaultCredentialsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".aws/credentials"
+	}
+
+	return home + "/.aws/credentials"
 }
 
 // Get and validate CLI arguments. Returns error if validation fails.
@@ -73,15 +107,18 @@ func ParseCliArguments(args []string) (cliArguments CliArguments, err error) {
 		app = kingpin.New("Perun", "Swiss army knife for AWS CloudFormation templates - validation, conversion, generators and other various stuff.")
 
 		quiet             = app.Flag("quiet", "No console output, just return code.").Short('q').Bool()
-		yes               = app.Flag("yes", "Always say yes.").Short('y').Bool()
-		verbosity         = app.Flag("verbosity", "Logger verbosity: TRACE | DEBUG | INFO | ERROR.").Short('v').String()
+		yes               = app.Flag("yes", "Always say yes.").Short('y').Envar("PERUN_YES").Bool()
+		verbosity         = app.Flag("verbosity", "Logger verbosity: TRACE | DEBUG | INFO | ERROR.").Short('v').Envar("PERUN_VERBOSITY").String()
 		mfa               = app.Flag("mfa", "Enable AWS MFA.").Bool()
-		DurationForMFA    = app.Flag("duration", "Duration for AWS MFA token (seconds value from range [1, 129600]).").Short('d').Int64()
-		profile           = app.Flag("profile", "An AWS profile name.").Short('p').String()
-		region            = app.Flag("region", "An AWS region to use.").Short('r').String()
+		DurationForMFA    = app.Flag("duration", "Duration for AWS MFA token (seconds value from range [1, 129600]).").Short('d').Envar("PERUN_DURATION").Int64()
+		profile           = app.Flag("profile", "An AWS profile name.").Short('p').Envar("PERUN_PROFILE").String()
+		region            = app.Flag("region", "An AWS region to use.").Short('r').Envar("PERUN_REGION").String()
 		sandbox           = app.Flag("sandbox", "Do not use configuration files hierarchy.").Bool()
+		partition         = app.Flag("partition", "An AWS partition to use: aws | aws-us-gov | aws-cn | aws-iso.").Envar("PERUN_PARTITION").Enum("aws", "aws-us-gov", "aws-cn", "aws-iso")
 		configurationPath = app.Flag("config", "A path to the configuration file").Short('c').String()
 		showProgress      = app.Flag("progress", "Show progress of stack creation. Option available only after setting up a remote sink").Bool()
+		logFormat         = app.Flag("log-format", "Logger output format: text | json.").Envar("PERUN_LOG_FORMAT").Default(TextLogFormat).Enum(TextLogFormat, JSONLogFormat)
+		logFile           = app.Flag("log-file", "Duplicate logger output to this file, in addition to stdout/stderr.").Envar("PERUN_LOG_FILE").String()
 
 		onlineValidate         = app.Command(ValidateMode, "Online template Validation")
 		onlineValidateTemplate = onlineValidate.Arg("template", "A path to the template file.").String()
@@ -94,7 +131,10 @@ func ParseCliArguments(args []string) (cliArguments CliArguments, err error) {
 		convertOutputFile  = convert.Arg("output", "A path where converted file will be saved.").String()
 		convertPrettyPrint = convert.Flag("pretty-print", "Pretty printing JSON").Bool()
 
-		configure = app.Command(ConfigureMode, "Create your own configuration mode")
+		configure            = app.Command(ConfigureMode, "Create your own configuration mode")
+		configureOutput      = configure.Flag("output", "A path where the configuration file will be written, non-interactively.").Envar("PERUN_OUTPUT").String()
+		configureDecisionMFA = configure.Flag("decision-for-mfa", "Default decision whether to use MFA.").Envar("PERUN_DECISION_FOR_MFA").Bool()
+		configureFromFile    = configure.Flag("from-file", "A path to a YAML file holding the same fields as the configuration file, applied non-interactively.").Envar("PERUN_FROM_FILE").String()
 
 		createStack               = app.Command(CreateStackMode, "Creates a stack on aws")
 		createStackName           = createStack.Arg("stack", "An AWS stack name.").Required().String()
@@ -130,6 +170,22 @@ func ParseCliArguments(args []string) (cliArguments CliArguments, err error) {
 		setDefaultUnblockingStackPolicy = setStackPolicy.Flag("unblock", "Unblocking all actions.").Bool()
 		setDisableStackTermination      = setStackPolicy.Flag("disable-stack-termination", "Allow to delete a stack.").Bool()
 		setEnableStackTermination       = setStackPolicy.Flag("enable-stack-termination", "Protecting a stack from being deleted.").Bool()
+
+		writeCredentials              = app.Command(WriteCredentialsMode, "Refresh MFA/STS credentials and persist them into an AWS credentials file.")
+		writeCredentialsTargetProfile = writeCredentials.Flag("target-profile", "Profile name to write the temporary credentials under.").Required().String()
+		writeCredentialsFile          = writeCredentials.Flag("credentials-file", "Path to the AWS credentials file.").Default(defaultCredentialsFile()).String()
+		writeCredentialsSourceProfile = writeCredentials.Flag("source-profile", "Profile used to obtain the temporary credentials.").String()
+		writeCredentialsRoleArn       = writeCredentials.Flag("role-arn", "An IAM role to assume when obtaining the temporary credentials.").String()
+		writeCredentialsCheck         = writeCredentials.Flag("check", "Print the remaining validity of the currently-written token instead of refreshing it.").Bool()
+
+		config            = app.Command(ConfigMode, "Manage the perun configuration file")
+		configMigrate     = config.Command(ConfigMigrateMode, "Migrate a configuration file to the latest schema version, printing a diff of the changes")
+		configMigrateFile = configMigrate.Flag("file", "A path to the configuration file to migrate.").Required().String()
+
+		spec             = app.Command(SpecMode, "Manage the cached CloudFormation resource specification")
+		specUpdate       = spec.Command(SpecUpdateMode, "Pre-warm the local resource specification cache")
+		specUpdateRegion = specUpdate.Flag("region", "An AWS region to update the cached specification for.").String()
+		specUpdateAll    = specUpdate.Flag("all", "Update the cached specification for every region in the current partition.").Bool()
 	)
 
 	app.HelpFlag.Short('h')
@@ -157,6 +213,9 @@ func ParseCliArguments(args []string) (cliArguments CliArguments, err error) {
 		// configure
 	case configure.FullCommand():
 		cliArguments.Mode = &ConfigureMode
+		cliArguments.ConfigurationOutput = configureOutput
+		cliArguments.DecisionForMFA = configureDecisionMFA
+		cliArguments.ConfigurationFromFile = configureFromFile
 
 		// create Stack
 	case createStack.FullCommand():
@@ -216,6 +275,26 @@ func ParseCliArguments(args []string) (cliArguments CliArguments, err error) {
 		// destroy remote sink
 	case destroySink.FullCommand():
 		cliArguments.Mode = &DestroySinkMode
+
+		// write credentials
+	case writeCredentials.FullCommand():
+		cliArguments.Mode = &WriteCredentialsMode
+		cliArguments.TargetProfile = writeCredentialsTargetProfile
+		cliArguments.CredentialsFile = writeCredentialsFile
+		cliArguments.SourceProfile = writeCredentialsSourceProfile
+		cliArguments.RoleArn = writeCredentialsRoleArn
+		cliArguments.CheckCredentials = writeCredentialsCheck
+
+		// config migrate
+	case configMigrate.FullCommand():
+		cliArguments.Mode = &ConfigMigrateMode
+		cliArguments.ConfigMigrateFile = configMigrateFile
+
+		// spec update
+	case specUpdate.FullCommand():
+		cliArguments.Mode = &SpecUpdateMode
+		cliArguments.SpecUpdateRegion = specUpdateRegion
+		cliArguments.SpecUpdateAll = specUpdateAll
 	}
 
 	// OTHER FLAGS
@@ -227,8 +306,11 @@ func ParseCliArguments(args []string) (cliArguments CliArguments, err error) {
 	cliArguments.Profile = profile
 	cliArguments.Region = region
 	cliArguments.Sandbox = sandbox
+	cliArguments.Partition = partition
 	cliArguments.ConfigurationPath = configurationPath
 	cliArguments.Progress = showProgress
+	cliArguments.LogFormat = logFormat
+	cliArguments.LogFile = logFile
 
 	if *cliArguments.DurationForMFA < 0 {
 		err = errors.New("You should specify value for duration of MFA token greater than zero")
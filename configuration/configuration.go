@@ -0,0 +1,128 @@
+// Copyright 2017 Appliscale
+//
+// Maintainers and contributors are listed in README file inside repository.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configuration reads, migrates and writes perun's configuration
+// file.
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/Appliscale/perun/cliparser"
+	"github.com/Appliscale/perun/logger"
+	"gopkg.in/yaml.v2"
+)
+
+// Configuration is perun's on-disk configuration file, unmarshalled from
+// YAML. SchemaVersion is bumped whenever a field is added, renamed or
+// removed; Migrate() is run against the raw document before it ever
+// reaches this struct, so older files keep loading.
+type Configuration struct {
+	SchemaVersion         int               `yaml:"schemaversion"`
+	DefaultProfile        string            `yaml:"defaultprofile"`
+	DefaultRegion         string            `yaml:"defaultregion"`
+	DefaultPartition      string            `yaml:"defaultpartition"`
+	SpecificationURL      map[string]string `yaml:"specificationurl"`
+	DefaultDecisionForMFA bool              `yaml:"defaultdecisionformfa"`
+	DefaultDurationForMFA int64             `yaml:"defaultdurationformfa"`
+	DefaultVerbosity      string            `yaml:"defaultverbosity"`
+}
+
+// SaveToFile writes config as YAML to path, logging and returning any
+// error encountered instead of panicking, since this runs at the end of
+// an interactive session the user shouldn't lose.
+func SaveToFile(config Configuration, path string, log logger.Logger) error {
+	config.SchemaVersion = SchemaVersion
+
+	raw, err := yaml.Marshal(config)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, raw, 0644); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetConfiguration reads the configuration file named by
+// cliArguments.ConfigurationPath (or the default search path when unset),
+// migrates it to the current SchemaVersion, and unmarshals the result. A
+// missing file is not an error: it's the normal state for a first-time
+// user or a fresh container, most commonly on their way to running
+// `perun configure` to create one, so this returns a zero-value
+// Configuration instead of failing.
+func GetConfiguration(cliArguments cliparser.CliArguments) (Configuration, error) {
+	var config Configuration
+
+	path, err := resolvePath(cliArguments)
+	if err != nil {
+		return config, err
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return config, err
+	}
+
+	migrated, err := Migrate(document, Migrations)
+	if err != nil {
+		return config, err
+	}
+
+	upgraded, err := yaml.Marshal(migrated)
+	if err != nil {
+		return config, err
+	}
+
+	err = yaml.Unmarshal(upgraded, &config)
+	return config, err
+}
+
+// resolvePath returns the configuration file path to read, honouring
+// --config and falling back to the default per-user location.
+func resolvePath(cliArguments cliparser.CliArguments) (string, error) {
+	if cliArguments.ConfigurationPath != nil && *cliArguments.ConfigurationPath != "" {
+		return *cliArguments.ConfigurationPath, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return home + "/.config/perun", nil
+}
+
+// ReadInconsistencyConfiguration validates that the loaded configuration
+// doesn't contradict itself (e.g. a region outside its partition). It is
+// a hook for the checks context.GetContext runs after loading; today
+// there are none defined, so it simply returns nil.
+func ReadInconsistencyConfiguration(config Configuration) error {
+	return nil
+}
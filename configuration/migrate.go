@@ -0,0 +1,65 @@
+package configuration
+
+import "fmt"
+
+// SchemaVersion is the current configuration schema. Bump it and add a
+// migration to Migrations whenever a field is added, renamed or removed.
+const SchemaVersion = 2
+
+// Migration upgrades a raw, untyped configuration document from one
+// SchemaVersion to the next. Keeping migrations untyped lets a renamed or
+// removed field be handled before it ever reaches Configuration.
+type Migration func(map[string]interface{}) (map[string]interface{}, error)
+
+// Migrations is keyed by the SchemaVersion a document is migrating
+// *from*. Migrate walks this chain until the document reaches
+// SchemaVersion.
+var Migrations = map[int]Migration{
+	1: addDefaultPartition,
+}
+
+// addDefaultPartition is the 1->2 migration: partition support
+// (chunk0-1) added DefaultPartition to Configuration, so files written
+// before it default to the "aws" partition, matching the hardcoded
+// commercial-only region list they were created under.
+func addDefaultPartition(document map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := document["defaultpartition"]; !ok {
+		document["defaultpartition"] = "aws"
+	}
+	return document, nil
+}
+
+// Migrate walks document forward from its recorded schemaversion (1 when
+// absent, since that's the version every config file predates
+// SchemaVersion's introduction) to SchemaVersion, applying the
+// registered migration at each step.
+func Migrate(document map[string]interface{}, migrations map[int]Migration) (map[string]interface{}, error) {
+	if document == nil {
+		document = map[string]interface{}{}
+	}
+
+	version := 1
+	if raw, ok := document["schemaversion"]; ok {
+		if n, ok := raw.(int); ok {
+			version = n
+		}
+	}
+
+	for version < SchemaVersion {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered from schema version %d", version)
+		}
+
+		migrated, err := migrate(document)
+		if err != nil {
+			return nil, err
+		}
+
+		document = migrated
+		version++
+	}
+
+	document["schemaversion"] = SchemaVersion
+	return document, nil
+}
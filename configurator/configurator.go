@@ -1,31 +1,105 @@
 package configurator
 
 import (
+	"io/ioutil"
+	"os"
+	"strconv"
+
 	"github.com/Appliscale/perun/configuration"
 	"github.com/Appliscale/perun/context"
+	"github.com/Appliscale/perun/logger"
 	"github.com/Appliscale/perun/myuser"
-	"os"
-	"strconv"
+	"gopkg.in/yaml.v2"
 )
 
-var resourceSpecificationURL = map[string]string{
-	"us-east-2":      "https://dnwj8swjjbsbt.cloudfront.net",
-	"us-east-1":      "https://d1uauaxba7bl26.cloudfront.net",
-	"us-west-1":      "https://d68hl49wbnanq.cloudfront.net",
-	"us-west-2":      "https://d201a2mn26r7lk.cloudfront.net",
-	"ap-south-1":     "https://d2senuesg1djtx.cloudfront.net",
-	"ap-northeast-2": "https://d1ane3fvebulky.cloudfront.net",
-	"ap-southeast-1": "https://doigdx0kgq9el.cloudfront.net",
-	"ap-southeast-2": "https://d2stg8d246z9di.cloudfront.net",
-	"ap-northeast-1": "https://d33vqc0rt9ld30.cloudfront.net",
-	"ca-central-1":   "https://d2s8ygphhesbe7.cloudfront.net",
-	"eu-central-1":   "https://d1mta8qj7i28i2.cloudfront.net",
-	"eu-west-1":      "https://d3teyb21fexa9r.cloudfront.net",
-	"eu-west-2":      "https://d1742qcu2c1ncx.cloudfront.net",
-	"sa-east-1":      "https://d3c9jyj3w509b0.cloudfront.net",
+// Partition represents an isolated group of AWS regions (commercial,
+// GovCloud, China or the ISO partitions used in air-gapped deployments).
+// Each partition owns its own region list and CloudFormation resource
+// specification URLs, since the three are never shared across partitions.
+type Partition struct {
+	Name                      string
+	Regions                   []string
+	ResourceSpecificationURLs map[string]string
+}
+
+const DefaultPartition = "aws"
+
+var Partitions = map[string]Partition{
+	"aws": {
+		Name: "aws",
+		Regions: []string{
+			"us-east-1",
+			"us-east-2",
+			"us-west-1",
+			"us-west-2",
+			"ca-central-1",
+			"eu-central-1",
+			"eu-west-1",
+			"eu-west-2",
+			"ap-northeast-1",
+			"ap-northeast-2",
+			"ap-southeast-1",
+			"ap-southeast-2",
+			"ap-south-1",
+			"sa-east-1",
+		},
+		ResourceSpecificationURLs: map[string]string{
+			"us-east-2":      "https://dnwj8swjjbsbt.cloudfront.net",
+			"us-east-1":      "https://d1uauaxba7bl26.cloudfront.net",
+			"us-west-1":      "https://d68hl49wbnanq.cloudfront.net",
+			"us-west-2":      "https://d201a2mn26r7lk.cloudfront.net",
+			"ap-south-1":     "https://d2senuesg1djtx.cloudfront.net",
+			"ap-northeast-2": "https://d1ane3fvebulky.cloudfront.net",
+			"ap-southeast-1": "https://doigdx0kgq9el.cloudfront.net",
+			"ap-southeast-2": "https://d2stg8d246z9di.cloudfront.net",
+			"ap-northeast-1": "https://d33vqc0rt9ld30.cloudfront.net",
+			"ca-central-1":   "https://d2s8ygphhesbe7.cloudfront.net",
+			"eu-central-1":   "https://d1mta8qj7i28i2.cloudfront.net",
+			"eu-west-1":      "https://d3teyb21fexa9r.cloudfront.net",
+			"eu-west-2":      "https://d1742qcu2c1ncx.cloudfront.net",
+			"sa-east-1":      "https://d3c9jyj3w509b0.cloudfront.net",
+		},
+	},
+	"aws-us-gov": {
+		Name: "aws-us-gov",
+		Regions: []string{
+			"us-gov-west-1",
+			"us-gov-east-1",
+		},
+		ResourceSpecificationURLs: map[string]string{
+			"us-gov-west-1": "https://d1e2j1ua0sh0cj.cloudfront.us-gov-west-1.amazonaws.com",
+			"us-gov-east-1": "https://d1e2j1ua0sh0cj.cloudfront.us-gov-west-1.amazonaws.com",
+		},
+	},
+	"aws-cn": {
+		Name: "aws-cn",
+		Regions: []string{
+			"cn-north-1",
+			"cn-northwest-1",
+		},
+		ResourceSpecificationURLs: map[string]string{
+			"cn-north-1":     "https://cfn-resource-specification.cn-north-1.amazonaws.com.cn",
+			"cn-northwest-1": "https://cfn-resource-specification.cn-north-1.amazonaws.com.cn",
+		},
+	},
+	"aws-iso": {
+		Name: "aws-iso",
+		Regions: []string{
+			"us-iso-east-1",
+		},
+		ResourceSpecificationURLs: map[string]string{
+			"us-iso-east-1": "https://cfn-resource-specification.us-iso-east-1.c2s.ic.gov",
+		},
+	},
 }
 
 func FileName(context *context.Context) {
+	if isNonInteractive(context) {
+		path := nonInteractivePath(context)
+		writeNonInteractiveConfig(path, context)
+		return
+	}
+
 	homePath, pathError := myuser.GetUserHomeDir()
 	if pathError != nil {
 		context.Logger.Error(pathError.Error())
@@ -39,11 +113,166 @@ func FileName(context *context.Context) {
 	findFile(yourPath+"/"+yourName, context)
 }
 
+// isNonInteractive reports whether configure should skip prompting and
+// write the configuration straight away: when --yes was passed, or when
+// any of --output/--region/--profile/--duration/--verbosity/
+// --decision-for-mfa/--from-file (directly or via a PERUN_* env var) was
+// supplied.
+func isNonInteractive(context *context.Context) bool {
+	args := context.CliArguments
+
+	if args.Yes != nil && *args.Yes {
+		return true
+	}
+
+	if args.ConfigurationOutput != nil && *args.ConfigurationOutput != "" {
+		return true
+	}
+
+	if args.Region != nil && *args.Region != "" {
+		return true
+	}
+
+	if args.Profile != nil && *args.Profile != "" {
+		return true
+	}
+
+	if args.DurationForMFA != nil && *args.DurationForMFA > 0 {
+		return true
+	}
+
+	if args.Verbosity != nil && *args.Verbosity != "" {
+		return true
+	}
+
+	if args.DecisionForMFA != nil && *args.DecisionForMFA {
+		return true
+	}
+
+	if args.ConfigurationFromFile != nil && *args.ConfigurationFromFile != "" {
+		return true
+	}
+
+	return false
+}
+
+// nonInteractivePath resolves the target configuration file path,
+// honouring --output and the global --sandbox flag.
+func nonInteractivePath(context *context.Context) string {
+	if context.CliArguments.ConfigurationOutput != nil && *context.CliArguments.ConfigurationOutput != "" {
+		return *context.CliArguments.ConfigurationOutput
+	}
+
+	if context.CliArguments.Sandbox != nil && *context.CliArguments.Sandbox {
+		return "./perun.config"
+	}
+
+	homePath, pathError := myuser.GetUserHomeDir()
+	if pathError != nil {
+		context.Logger.Error(pathError.Error())
+	}
+
+	return homePath + "/.config/perun"
+}
+
+// writeNonInteractiveConfig builds a Configuration from CLI flags and
+// PERUN_* environment variables (already resolved into
+// context.CliArguments by cliparser) and writes it without prompting.
+// When --from-file is given, that file's contents are written instead and
+// the other flags are not applied on top of it.
+func writeNonInteractiveConfig(path string, context *context.Context) {
+	con := nonInteractiveConfig(context)
+
+	if context.CliArguments.ConfigurationFromFile != nil && *context.CliArguments.ConfigurationFromFile != "" {
+		fromFile, err := loadConfigFromYAMLFile(*context.CliArguments.ConfigurationFromFile)
+		if err != nil {
+			context.Logger.Error(err.Error())
+			return
+		}
+		con = fromFile
+	}
+
+	configuration.SaveToFile(con, path, *context.Logger)
+}
+
+// nonInteractiveConfig builds a Configuration purely from CLI flags and
+// the PERUN_* environment variables kingpin already folded into them,
+// falling back to the same defaults the interactive flow uses.
+func nonInteractiveConfig(context *context.Context) configuration.Configuration {
+	args := context.CliArguments
+	partition := partitionFor(context)
+
+	region := partition.Regions[0]
+	if args.Region != nil && *args.Region != "" {
+		region = *args.Region
+	}
+
+	profile := "default"
+	if args.Profile != nil && *args.Profile != "" {
+		profile = *args.Profile
+	}
+
+	duration := int64(3600)
+	if args.DurationForMFA != nil && *args.DurationForMFA > 0 {
+		duration = *args.DurationForMFA
+	}
+
+	verbosity := "INFO"
+	if args.Verbosity != nil && *args.Verbosity != "" {
+		verbosity = *args.Verbosity
+	}
+
+	decisionForMFA := false
+	if args.DecisionForMFA != nil {
+		decisionForMFA = *args.DecisionForMFA
+	}
+
+	return configuration.Configuration{
+		DefaultProfile:        profile,
+		DefaultRegion:         region,
+		DefaultPartition:      partition.Name,
+		SpecificationURL:      partition.ResourceSpecificationURLs,
+		DefaultDecisionForMFA: decisionForMFA,
+		DefaultDurationForMFA: duration,
+		DefaultVerbosity:      verbosity,
+	}
+}
+
+// loadConfigFromYAMLFile parses the same YAML shape as the configuration
+// file from a user-supplied path, for `configure --from-file`.
+func loadConfigFromYAMLFile(path string) (configuration.Configuration, error) {
+	var con configuration.Configuration
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return con, err
+	}
+
+	err = yaml.Unmarshal(raw, &con)
+	return con, err
+}
+
+// partitionFor resolves the partition to offer the user, honouring the
+// --partition flag when it was passed on the command line.
+func partitionFor(context *context.Context) Partition {
+	name := DefaultPartition
+	if context.CliArguments.Partition != nil && *context.CliArguments.Partition != "" {
+		name = *context.CliArguments.Partition
+	}
+
+	partition, ok := Partitions[name]
+	if !ok {
+		context.Logger.Error("Unknown partition " + name + ", falling back to " + DefaultPartition)
+		return Partitions[DefaultPartition]
+	}
+
+	return partition
+}
+
 func findFile(path string, context *context.Context) {
 	context.Logger.Always("File will be created in " + path)
 	_, err := os.Stat(path)
 	if os.IsNotExist(err) {
-		showRegions(context)
 		con := createConfig(context)
 		configuration.SaveToFile(con, path, *context.Logger)
 	} else {
@@ -51,25 +280,41 @@ func findFile(path string, context *context.Context) {
 	}
 }
 
-func showRegions(context *context.Context) {
-	regions := makeArrayRegions()
-	context.Logger.Always("Regions:")
-	for i := 0; i < len(regions); i++ {
+func showRegions(context *context.Context, partition Partition) {
+	context.Logger.Always("Regions", logger.Field{Key: "mode", Value: "configure"}, logger.Field{Key: "partition", Value: partition.Name})
+	for i, region := range partition.Regions {
 		pom := strconv.Itoa(i)
-		context.Logger.Always("Number " + pom + " region " + regions[i])
+		context.Logger.Always("Number "+pom+" region "+region, logger.Field{Key: "region", Value: region})
+	}
+}
+
+func setPartition(context *context.Context) (partition Partition, err bool) {
+	var name string
+	context.Logger.GetInput("Choose partition (aws, aws-us-gov, aws-cn, aws-iso)", &name)
+	if name == "" {
+		name = DefaultPartition
+	}
+
+	partition, ok := Partitions[name]
+	if !ok {
+		context.Logger.Error("Invalid partition")
+		return Partition{}, false
 	}
+
+	context.Logger.Always("Your partition is: " + partition.Name)
+	return partition, true
 }
 
-func setRegions(context *context.Context) (region string, err bool) {
+func setRegions(context *context.Context, partition Partition) (region string, err bool) {
 	var numberRegion int
 	context.Logger.GetInput("Choose region", &numberRegion)
-	regions := makeArrayRegions()
-	if numberRegion >= 0 && numberRegion < 14 {
+	regions := partition.Regions
+	if numberRegion >= 0 && numberRegion < len(regions) {
 		region = regions[numberRegion]
-		context.Logger.Always("Your region is: " + region)
+		context.Logger.Always("Region selected", logger.Field{Key: "region", Value: region})
 		err = true
 	} else {
-		context.Logger.Error("Invalid region")
+		context.Logger.Error("Invalid region", logger.Field{Key: "mode", Value: "configure"})
 		err = false
 	}
 	return
@@ -88,21 +333,35 @@ func setProfile(context *context.Context) (profile string, err bool) {
 }
 
 func createConfig(context *context.Context) configuration.Configuration {
-	myRegion, err := setRegions(context)
+	partition := partitionFor(context)
+
+	if context.CliArguments.Partition == nil || *context.CliArguments.Partition == "" {
+		var ok bool
+		partition, ok = setPartition(context)
+		for !ok {
+			context.Logger.Always("Try again, invalid partition")
+			partition, ok = setPartition(context)
+		}
+	}
+
+	showRegions(context, partition)
+
+	myRegion, err := setRegions(context, partition)
 	for !err {
 		context.Logger.Always("Try again, invalid region")
-		myRegion, err = setRegions(context)
+		myRegion, err = setRegions(context, partition)
 	}
 	myProfile, err1 := setProfile(context)
 	for !err1 {
 		context.Logger.Always("Try again, invalid profile")
 		myProfile, err1 = setProfile(context)
 	}
-	myResourceSpecificationURL := resourceSpecificationURL
+	myResourceSpecificationURL := partition.ResourceSpecificationURLs
 
 	myConfig := configuration.Configuration{
 		DefaultProfile:        myProfile,
 		DefaultRegion:         myRegion,
+		DefaultPartition:      partition.Name,
 		SpecificationURL:      myResourceSpecificationURL,
 		DefaultDecisionForMFA: false,
 		DefaultDurationForMFA: 3600,
@@ -110,23 +369,3 @@ func createConfig(context *context.Context) configuration.Configuration {
 
 	return myConfig
 }
-
-func makeArrayRegions() [14]string {
-	var regions [14]string
-	regions[0] = "us-east-1"
-	regions[1] = "us-east-2"
-	regions[2] = "us-west-1"
-	regions[3] = "us-west-2"
-	regions[4] = "ca-central-1"
-	regions[5] = "ca-central-1"
-	regions[6] = "eu-west-1"
-	regions[7] = "eu-west-2"
-	regions[8] = "ap-northeast-1"
-	regions[9] = "ap-northeast-2"
-	regions[10] = "ap-southeast-1"
-	regions[11] = "ap-southeast-2"
-	regions[12] = "ap-south-1"
-	regions[13] = "sa-east-1"
-
-	return regions
-}
@@ -0,0 +1,45 @@
+package configurator
+
+import "strings"
+
+// unifiedDiff renders a minimal unified-style diff between two small YAML
+// documents. It is not a general-purpose diff algorithm: it walks both
+// line lists and marks mismatches line by line, which is good enough for
+// the short, flat configuration files perun reads and writes.
+func unifiedDiff(path, before, after string) string {
+	beforeLines := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	afterLines := strings.Split(strings.TrimRight(after, "\n"), "\n")
+
+	var out strings.Builder
+	out.WriteString("--- " + path + "\n")
+	out.WriteString("+++ " + path + " (migrated)\n")
+
+	max := len(beforeLines)
+	if len(afterLines) > max {
+		max = len(afterLines)
+	}
+
+	for i := 0; i < max; i++ {
+		var oldLine, newLine string
+		if i < len(beforeLines) {
+			oldLine = beforeLines[i]
+		}
+		if i < len(afterLines) {
+			newLine = afterLines[i]
+		}
+
+		if oldLine == newLine {
+			out.WriteString(" " + oldLine + "\n")
+			continue
+		}
+
+		if oldLine != "" {
+			out.WriteString("-" + oldLine + "\n")
+		}
+		if newLine != "" {
+			out.WriteString("+" + newLine + "\n")
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,34 @@
+package configurator
+
+import "testing"
+
+func TestUnifiedDiffMarksChangedLinesOnly(t *testing.T) {
+	before := "defaultregion: us-east-1\ndefaultprofile: default\n"
+	after := "defaultregion: us-east-1\ndefaultprofile: default\ndefaultpartition: aws\n"
+
+	diff := unifiedDiff("/tmp/perun.config", before, after)
+
+	want := "--- /tmp/perun.config\n" +
+		"+++ /tmp/perun.config (migrated)\n" +
+		" defaultregion: us-east-1\n" +
+		" defaultprofile: default\n" +
+		"+defaultpartition: aws\n"
+
+	if diff != want {
+		t.Errorf("unifiedDiff() =\n%q\nwant\n%q", diff, want)
+	}
+}
+
+func TestUnifiedDiffNoChanges(t *testing.T) {
+	same := "defaultregion: us-east-1\n"
+
+	diff := unifiedDiff("/tmp/perun.config", same, same)
+
+	want := "--- /tmp/perun.config\n" +
+		"+++ /tmp/perun.config (migrated)\n" +
+		" defaultregion: us-east-1\n"
+
+	if diff != want {
+		t.Errorf("unifiedDiff() =\n%q\nwant\n%q", diff, want)
+	}
+}
@@ -0,0 +1,58 @@
+package configurator
+
+import (
+	"io/ioutil"
+
+	"github.com/Appliscale/perun/configuration"
+	"github.com/Appliscale/perun/context"
+	"gopkg.in/yaml.v2"
+)
+
+// Migrate runs the configured migration pipeline against the file named by
+// --file, prints a unified diff of the changes and, unless --yes was
+// passed, asks for confirmation before writing the upgraded file back.
+func Migrate(context *context.Context) error {
+	path := *context.CliArguments.ConfigMigrateFile
+
+	original, err := ioutil.ReadFile(path)
+	if err != nil {
+		context.Logger.Error(err.Error())
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(original, &raw); err != nil {
+		context.Logger.Error(err.Error())
+		return err
+	}
+
+	migrated, err := configuration.Migrate(raw, configuration.Migrations)
+	if err != nil {
+		context.Logger.Error(err.Error())
+		return err
+	}
+
+	upgraded, err := yaml.Marshal(migrated)
+	if err != nil {
+		context.Logger.Error(err.Error())
+		return err
+	}
+
+	if string(upgraded) == string(original) {
+		context.Logger.Always("Configuration already at the latest schema version, nothing to do.")
+		return nil
+	}
+
+	context.Logger.Always(unifiedDiff(path, string(original), string(upgraded)))
+
+	if context.CliArguments.Yes == nil || !*context.CliArguments.Yes {
+		var confirmation string
+		context.Logger.GetInput("Write the migrated configuration back to "+path+"? [y/N]", &confirmation)
+		if confirmation != "y" && confirmation != "Y" {
+			context.Logger.Always("Migration aborted, file left unchanged.")
+			return nil
+		}
+	}
+
+	return ioutil.WriteFile(path, upgraded, 0644)
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Appliscale
+//
+// Maintainers and contributors are listed in README file inside repository.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package context assembles the parsed CLI arguments, loaded
+// configuration and logger that every perun command runs against.
+package context
+
+import (
+	"os"
+
+	"github.com/Appliscale/perun/cliparser"
+	"github.com/Appliscale/perun/configuration"
+	"github.com/Appliscale/perun/logger"
+)
+
+// Context is threaded through every command: the flags the user passed,
+// the configuration loaded from disk, and the logger they both feed
+// into (format, quiet-ness and an optional file sink all come from the
+// CLI arguments).
+type Context struct {
+	CliArguments cliparser.CliArguments
+	Config       configuration.Configuration
+	Logger       *logger.Logger
+}
+
+// GetContext parses the CLI arguments, builds the logger they configure,
+// loads the configuration file and checks it for internal consistency.
+// The three functions are injected so callers (and tests) can supply
+// fakes; the arguments handed to parseCliArguments are always os.Args.
+func GetContext(
+	parseCliArguments func([]string) (cliparser.CliArguments, error),
+	getConfiguration func(cliparser.CliArguments) (configuration.Configuration, error),
+	readInconsistencyConfiguration func(configuration.Configuration) error,
+) (Context, error) {
+	var context Context
+
+	cliArguments, err := parseCliArguments(os.Args)
+	if err != nil {
+		return context, err
+	}
+	context.CliArguments = cliArguments
+
+	logFormat := logger.TextFormat
+	if cliArguments.LogFormat != nil && *cliArguments.LogFormat != "" {
+		logFormat = *cliArguments.LogFormat
+	}
+
+	logFile := ""
+	if cliArguments.LogFile != nil {
+		logFile = *cliArguments.LogFile
+	}
+
+	quiet := cliArguments.Quiet != nil && *cliArguments.Quiet
+
+	log, err := logger.New(logFormat, quiet, logFile)
+	if err != nil {
+		return context, err
+	}
+	context.Logger = log
+
+	config, err := getConfiguration(cliArguments)
+	if err != nil {
+		context.Logger.Error(err.Error())
+		return context, err
+	}
+	context.Config = config
+
+	if err := readInconsistencyConfiguration(config); err != nil {
+		context.Logger.Error(err.Error())
+		return context, err
+	}
+
+	return context, nil
+}
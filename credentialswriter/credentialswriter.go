@@ -0,0 +1,143 @@
+// Copyright 2017 Appliscale
+//
+// Maintainers and contributors are listed in README file inside repository.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credentialswriter persists temporary MFA/STS credentials into an
+// INI-formatted AWS credentials file, under a named profile, the way
+// `perun mfa` already does for the in-memory session.
+package credentialswriter
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Appliscale/perun/context"
+	"github.com/Appliscale/perun/logger"
+	"github.com/Appliscale/perun/mysession"
+)
+
+const expiresKey = "# expires = "
+
+// Write obtains temporary credentials (via MFA session refresh, or by
+// assuming a role when --role-arn is given) and atomically rewrites the
+// target profile in the given credentials file. The keys and comments of
+// other profiles are kept; the target profile's block is replaced whole,
+// and profiles are not written back in their original order.
+func Write(context *context.Context) error {
+	args := context.CliArguments
+	region := context.Config.DefaultRegion
+
+	sourceProfile := context.Config.DefaultProfile
+	if args.SourceProfile != nil && *args.SourceProfile != "" {
+		sourceProfile = *args.SourceProfile
+	}
+
+	duration := context.Config.DefaultDurationForMFA
+	if args.DurationForMFA != nil && *args.DurationForMFA > 0 {
+		duration = *args.DurationForMFA
+	}
+
+	var credentials mysession.TemporaryCredentials
+	var err error
+
+	if args.RoleArn != nil && *args.RoleArn != "" {
+		credentials, err = mysession.AssumeRole(*args.RoleArn, sourceProfile, duration, context)
+	} else {
+		err = mysession.UpdateSessionToken(sourceProfile, region, duration, context)
+		credentials = mysession.CurrentTemporaryCredentials(context)
+	}
+
+	if err != nil {
+		context.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "region", Value: region})
+		return err
+	}
+
+	return persist(*args.CredentialsFile, *args.TargetProfile, region, credentials, context)
+}
+
+// Check prints the remaining validity of the credentials currently
+// written under --target-profile, without refreshing them.
+func Check(context *context.Context) error {
+	args := context.CliArguments
+	targetProfile := *args.TargetProfile
+
+	profiles, err := readProfiles(*args.CredentialsFile)
+	if err != nil {
+		context.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+		return err
+	}
+
+	profile, ok := profiles[targetProfile]
+	if !ok {
+		err = errors.New("profile " + targetProfile + " not found in " + *args.CredentialsFile)
+		context.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+		return err
+	}
+
+	if profile.expiresComment == "" {
+		err = errors.New("profile " + targetProfile + " has no recorded expiry")
+		context.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+		return err
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, profile.expiresComment)
+	if err != nil {
+		context.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+		return err
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		context.Logger.Always("Token for profile "+targetProfile+" has expired.", logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+	} else {
+		context.Logger.Always("Token for profile "+targetProfile+" is valid for "+remaining.String()+".", logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+	}
+
+	return nil
+}
+
+// persist atomically rewrites the target profile's block in the
+// credentials file, keeping the keys and comments of every other profile.
+func persist(path, targetProfile, region string, credentials mysession.TemporaryCredentials, context *context.Context) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		context.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "write-credentials"}, logger.Field{Key: "profile", Value: targetProfile})
+		return err
+	}
+	defer unlock()
+
+	profiles, err := readProfiles(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if profiles == nil {
+		profiles = map[string]*iniProfile{}
+	}
+
+	profiles[targetProfile] = &iniProfile{
+		name: targetProfile,
+		attributes: map[string]string{
+			"aws_access_key_id":     credentials.AccessKeyID,
+			"aws_secret_access_key": credentials.SecretAccessKey,
+			"aws_session_token":     credentials.SessionToken,
+			"region":                region,
+		},
+		expiresComment: credentials.Expiration.Format(time.RFC3339),
+	}
+
+	return writeProfilesAtomically(path, profiles)
+}
@@ -0,0 +1,161 @@
+package credentialswriter
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// iniProfile is a single `[profile]` block from an AWS credentials file.
+// Every `key = value` line, whatever the key, goes into attributes; lines
+// keeps the remaining non-blank lines (comments other than the expiry
+// marker) verbatim. Blank lines are dropped, and on write-back attributes
+// come out as `key = value` in no particular order, ahead of lines.
+type iniProfile struct {
+	name           string
+	attributes     map[string]string
+	lines          []string
+	expiresComment string
+}
+
+// preambleKey is the synthetic profile name used to stash any lines that
+// appear before the file's first `[section]` header (a leading comment
+// or commented-out profile), so they survive a write-back instead of
+// being dropped. It must never collide with a real profile name, so it
+// uses a byte that can appear in neither a `[section]` header parsed
+// from the file nor a --target-profile value passed on the command
+// line, unlike the empty string (an AWS credentials file can contain a
+// literal `[]` section, and --target-profile "" is a value kingpin's
+// Required() happily accepts).
+const preambleKey = "\x00"
+
+// readProfiles parses an AWS-style credentials file into one iniProfile
+// per `[section]`, keeping comments verbatim and every key's value.
+func readProfiles(path string) (map[string]*iniProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := map[string]*iniProfile{}
+	var current *iniProfile
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			name := strings.TrimSuffix(strings.TrimPrefix(trimmed, "["), "]")
+			current = &iniProfile{name: name, attributes: map[string]string{}}
+			profiles[name] = current
+			continue
+		}
+
+		if current == nil {
+			current = &iniProfile{name: preambleKey, attributes: map[string]string{}}
+			profiles[preambleKey] = current
+		}
+
+		if trimmed == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, expiresKey) {
+			current.expiresComment = strings.TrimPrefix(trimmed, expiresKey)
+			continue
+		}
+
+		key, value, isAttribute := splitAttribute(trimmed)
+		if isAttribute {
+			current.attributes[key] = value
+			continue
+		}
+
+		current.lines = append(current.lines, line)
+	}
+
+	return profiles, scanner.Err()
+}
+
+// splitAttribute parses a `key = value` line, ignoring blank lines and
+// comments so they are preserved as opaque lines instead.
+func splitAttribute(line string) (key, value string, ok bool) {
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// writeProfilesAtomically serializes every profile back to disk via a
+// temporary file in the same directory, then renames it over the target
+// so a reader never observes a partially written credentials file.
+func writeProfilesAtomically(path string, profiles map[string]*iniProfile) error {
+	var builder strings.Builder
+
+	if preamble, ok := profiles[preambleKey]; ok {
+		writeProfileBody(&builder, preamble)
+	}
+
+	for name, profile := range profiles {
+		if name == preambleKey {
+			continue
+		}
+
+		builder.WriteString("[" + name + "]\n")
+		writeProfileBody(&builder, profile)
+	}
+
+	temp, err := ioutil.TempFile(dirOf(path), ".perun-credentials-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.WriteString(builder.String()); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// writeProfileBody writes a profile's attributes, expiry comment and
+// preserved lines (but not its `[section]` header, since the preamble
+// profile has none).
+func writeProfileBody(builder *strings.Builder, profile *iniProfile) {
+	for key, value := range profile.attributes {
+		builder.WriteString(key + " = " + value + "\n")
+	}
+
+	if profile.expiresComment != "" {
+		builder.WriteString(expiresKey + profile.expiresComment + "\n")
+	}
+
+	for _, line := range profile.lines {
+		builder.WriteString(line + "\n")
+	}
+
+	builder.WriteString("\n")
+}
+
+func dirOf(path string) string {
+	index := strings.LastIndex(path, "/")
+	if index == -1 {
+		return "."
+	}
+
+	return path[:index]
+}
@@ -0,0 +1,117 @@
+package credentialswriter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProfilesPreservesOtherProfilesAndComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	original := "# a leading comment\n" +
+		"[default]\n" +
+		"aws_access_key_id = AAA\n" +
+		"aws_secret_access_key = BBB\n" +
+		"\n" +
+		"[other]\n" +
+		"aws_access_key_id = CCC\n" +
+		"aws_secret_access_key = DDD\n" +
+		"# expires = 2020-01-01T00:00:00Z\n"
+
+	if err := ioutil.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := readProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := profiles["default"].attributes["aws_access_key_id"]; got != "AAA" {
+		t.Errorf("default aws_access_key_id = %q, want AAA", got)
+	}
+
+	other := profiles["other"]
+	if got := other.attributes["aws_access_key_id"]; got != "CCC" {
+		t.Errorf("other aws_access_key_id = %q, want CCC", got)
+	}
+	if got := other.expiresComment; got != "2020-01-01T00:00:00Z" {
+		t.Errorf("other.expiresComment = %q, want 2020-01-01T00:00:00Z", got)
+	}
+	if _, ok := other.attributes["expires"]; ok {
+		t.Errorf("expires should not land in attributes, it has its own field")
+	}
+}
+
+func TestPreambleSurvivesReadWriteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	original := "# a leading comment\n" +
+		"# a commented-out profile\n" +
+		"[default]\n" +
+		"aws_access_key_id = AAA\n" +
+		"aws_secret_access_key = BBB\n"
+
+	if err := ioutil.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := readProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeProfilesAtomically(path, profiles); err != nil {
+		t.Fatal(err)
+	}
+
+	rewritten, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# a leading comment\n# a commented-out profile\n"
+	if got := string(rewritten); got[:len(want)] != want {
+		t.Errorf("rewritten file = %q, want it to start with the preserved preamble %q", got, want)
+	}
+}
+
+func TestWriteProfilesAtomicallyRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+
+	profiles := map[string]*iniProfile{
+		"default": {
+			name: "default",
+			attributes: map[string]string{
+				"aws_access_key_id":     "AAA",
+				"aws_secret_access_key": "BBB",
+				"aws_session_token":     "CCC",
+				"region":                "us-east-1",
+			},
+			expiresComment: "2030-01-01T00:00:00Z",
+		},
+		"untouched": {
+			name:       "untouched",
+			attributes: map[string]string{"aws_access_key_id": "DDD"},
+			lines:      []string{"# a custom comment"},
+		},
+	}
+
+	if err := writeProfilesAtomically(path, profiles); err != nil {
+		t.Fatal(err)
+	}
+
+	reread, err := readProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := reread["default"].expiresComment; got != "2030-01-01T00:00:00Z" {
+		t.Errorf("expiresComment = %q, want 2030-01-01T00:00:00Z", got)
+	}
+	if got := reread["untouched"].attributes["aws_access_key_id"]; got != "DDD" {
+		t.Errorf("untouched aws_access_key_id = %q, want DDD", got)
+	}
+	if len(reread["untouched"].lines) != 1 || reread["untouched"].lines[0] != "# a custom comment" {
+		t.Errorf("untouched.lines = %v, want the preserved comment", reread["untouched"].lines)
+	}
+}
@@ -0,0 +1,27 @@
+package credentialswriter
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile takes an exclusive, advisory lock on path by creating a
+// sibling `.lock` file with O_EXCL, so two concurrent `write-credentials`
+// invocations can't interleave their rewrites of the same credentials
+// file. The returned function releases the lock.
+func lockFile(path string) (unlock func(), err error) {
+	lockPath := path + ".lock"
+
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, errors.New("credentials file " + path + " is locked by another perun process")
+		}
+		return nil, err
+	}
+	file.Close()
+
+	return func() {
+		os.Remove(lockPath)
+	}, nil
+}
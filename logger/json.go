@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// jsonLine is the shape of one JSON log line: the well-known fields perun
+// callers are expected to attach (mode, stack, region) get their own keys
+// when present, everything else lands in fields.
+type jsonLine struct {
+	Timestamp string                 `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Mode      string                 `json:"mode,omitempty"`
+	Stack     string                 `json:"stack,omitempty"`
+	Region    string                 `json:"region,omitempty"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+func formatJSON(level, message string, fields []Field) string {
+	line := jsonLine{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Level:     level,
+		Message:   message,
+		Fields:    map[string]interface{}{},
+	}
+
+	for _, field := range fields {
+		switch field.Key {
+		case "mode":
+			line.Mode, _ = field.Value.(string)
+		case "stack":
+			line.Stack, _ = field.Value.(string)
+		case "region":
+			line.Region, _ = field.Value.(string)
+		default:
+			line.Fields[field.Key] = field.Value
+		}
+	}
+
+	if len(line.Fields) == 0 {
+		line.Fields = nil
+	}
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return message
+	}
+
+	return string(encoded)
+}
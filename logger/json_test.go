@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFormatJSONCarriesWellKnownAndArbitraryFields(t *testing.T) {
+	line := formatJSON("ERROR", "stack creation failed", []Field{
+		{Key: "mode", Value: "create-stack"},
+		{Key: "stack", Value: "my-stack"},
+		{Key: "region", Value: "us-east-1"},
+		{Key: "reason", Value: "rollback"},
+	})
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Fatalf("formatJSON produced invalid JSON: %v", err)
+	}
+
+	if decoded["level"] != "ERROR" {
+		t.Errorf("level = %v, want ERROR", decoded["level"])
+	}
+	if decoded["message"] != "stack creation failed" {
+		t.Errorf("message = %v, want the log message", decoded["message"])
+	}
+	if decoded["mode"] != "create-stack" {
+		t.Errorf("mode = %v, want create-stack", decoded["mode"])
+	}
+	if decoded["stack"] != "my-stack" {
+		t.Errorf("stack = %v, want my-stack", decoded["stack"])
+	}
+	if decoded["region"] != "us-east-1" {
+		t.Errorf("region = %v, want us-east-1", decoded["region"])
+	}
+
+	fields, ok := decoded["fields"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("fields = %v, want a map with the arbitrary reason field", decoded["fields"])
+	}
+	if fields["reason"] != "rollback" {
+		t.Errorf("fields[reason] = %v, want rollback", fields["reason"])
+	}
+}
+
+func TestFormatJSONOmitsEmptyFields(t *testing.T) {
+	line := formatJSON("INFO", "hello", nil)
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Fatalf("formatJSON produced invalid JSON: %v", err)
+	}
+
+	if _, ok := decoded["fields"]; ok {
+		t.Errorf("fields should be omitted when there are no arbitrary fields, got %v", decoded["fields"])
+	}
+	if _, ok := decoded["mode"]; ok {
+		t.Errorf("mode should be omitted when not set, got %v", decoded["mode"])
+	}
+}
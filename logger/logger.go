@@ -0,0 +1,126 @@
+// Copyright 2017 Appliscale
+//
+// Maintainers and contributors are listed in README file inside repository.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logger provides perun's console logger, with either free-form
+// text output or one JSON object per line for machine consumption.
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+var validVerbosities = map[string]bool{
+	"TRACE": true,
+	"DEBUG": true,
+	"INFO":  true,
+	"ERROR": true,
+}
+
+// IsVerbosityValid reports whether verbosity is one of the levels perun
+// understands.
+func IsVerbosityValid(verbosity string) bool {
+	return validVerbosities[verbosity]
+}
+
+// Field is a single piece of structured context attached to a log line,
+// e.g. Field{Key: "stack", Value: "my-stack"}. In text mode it is
+// rendered inline; in JSON mode the keys mode, stack and region become
+// top-level keys of the line and every other key lands in the `fields` map.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger is perun's console/file logger. Mode selects free-form text or
+// one-JSON-object-per-line output; both are written to Sink when a log
+// file was given (and, unless Quiet, to stdout/stderr as well).
+type Logger struct {
+	Mode   string
+	Quiet  bool
+	Sink   io.Writer
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// New builds a Logger writing in the given format, optionally duplicating
+// output to logFile in addition to stdout/stderr.
+func New(format string, quiet bool, logFile string) (*Logger, error) {
+	logger := &Logger{
+		Mode:   format,
+		Quiet:  quiet,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+
+	if logFile != "" {
+		file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		logger.Sink = file
+	}
+
+	return logger, nil
+}
+
+// Always logs an informational message with optional structured fields.
+func (logger *Logger) Always(message string, fields ...Field) {
+	logger.write("INFO", message, fields, logger.Stdout)
+}
+
+// Error logs an error message with optional structured fields.
+func (logger *Logger) Error(message string, fields ...Field) {
+	logger.write("ERROR", message, fields, logger.Stderr)
+}
+
+// GetInput prompts the user on stdout and scans their answer into target.
+// It is unaffected by --log-format, since it isn't log output.
+func (logger *Logger) GetInput(prompt string, target interface{}) {
+	fmt.Fprint(logger.Stdout, prompt+": ")
+	fmt.Fscan(os.Stdin, target)
+}
+
+func (logger *Logger) write(level, message string, fields []Field, console io.Writer) {
+	var line string
+	if logger.Mode == JSONFormat {
+		line = formatJSON(level, message, fields)
+	} else {
+		line = formatText(level, message, fields)
+	}
+
+	if !logger.Quiet && console != nil {
+		fmt.Fprintln(console, line)
+	}
+
+	if logger.Sink != nil {
+		fmt.Fprintln(logger.Sink, line)
+	}
+}
+
+func formatText(level, message string, fields []Field) string {
+	line := "[" + level + "] " + message
+	for _, field := range fields {
+		line += fmt.Sprintf(" %s=%v", field.Key, field.Value)
+	}
+	return line
+}
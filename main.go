@@ -25,11 +25,13 @@ import (
 	"github.com/Appliscale/perun/configurator"
 	"github.com/Appliscale/perun/context"
 	"github.com/Appliscale/perun/converter"
+	"github.com/Appliscale/perun/credentialswriter"
 	"github.com/Appliscale/perun/mysession"
 	"github.com/Appliscale/perun/offlinevalidator"
 	"github.com/Appliscale/perun/onlinevalidator"
 	"github.com/Appliscale/perun/parameters"
 	"github.com/Appliscale/perun/progress"
+	"github.com/Appliscale/perun/specfetcher"
 	"github.com/Appliscale/perun/stack"
 	"github.com/Appliscale/perun/utilities"
 )
@@ -60,6 +62,21 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *context.CliArguments.Mode == cliparser.ConfigMigrateMode {
+		utilities.CheckErrorCodeAndExit(configurator.Migrate(&context))
+	}
+
+	if *context.CliArguments.Mode == cliparser.SpecUpdateMode {
+		regions := []string{context.Config.DefaultRegion}
+		if *context.CliArguments.SpecUpdateAll {
+			regions = configurator.Partitions[context.Config.DefaultPartition].Regions
+		} else if *context.CliArguments.SpecUpdateRegion != "" {
+			regions = []string{*context.CliArguments.SpecUpdateRegion}
+		}
+
+		utilities.CheckErrorCodeAndExit(specfetcher.Update(&context, regions))
+	}
+
 	if *context.CliArguments.Mode == cliparser.CreateStackMode {
 		utilities.CheckErrorCodeAndExit(stack.NewStack(&context))
 
@@ -99,6 +116,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *context.CliArguments.Mode == cliparser.WriteCredentialsMode {
+		if *context.CliArguments.CheckCredentials {
+			utilities.CheckErrorCodeAndExit(credentialswriter.Check(&context))
+		} else {
+			utilities.CheckErrorCodeAndExit(credentialswriter.Write(&context))
+		}
+	}
+
 	if *context.CliArguments.Mode == cliparser.SetStackPolicyMode {
 		if *context.CliArguments.DisableStackTermination || *context.CliArguments.EnableStackTermination {
 			utilities.CheckErrorCodeAndExit(stack.SetTerminationProtection(&context))
@@ -0,0 +1,84 @@
+// Copyright 2017 Appliscale
+//
+// Maintainers and contributors are listed in README file inside repository.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package offlinevalidator validates a CloudFormation template against
+// the CloudFormation resource specification without calling the AWS
+// ValidateTemplate API, so it works without credentials or network
+// access once the specification is cached.
+package offlinevalidator
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	perunContext "github.com/Appliscale/perun/context"
+	"github.com/Appliscale/perun/logger"
+	"github.com/Appliscale/perun/specfetcher"
+)
+
+type template struct {
+	Resources map[string]struct {
+		Type string `json:"Type"`
+	} `json:"Resources"`
+}
+
+// Validate loads the resource specification for the configured region
+// (via specfetcher, which transparently revalidates its on-disk cache)
+// and checks that every resource in the template uses a resource type
+// the specification actually knows about. It returns whether the
+// template is valid, logging the reason when it isn't.
+func Validate(perunCtx *perunContext.Context) bool {
+	templatePath := *perunCtx.CliArguments.TemplatePath
+	region := perunCtx.Config.DefaultRegion
+
+	raw, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		perunCtx.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "offline-validate"}, logger.Field{Key: "template", Value: templatePath})
+		return false
+	}
+
+	var parsedTemplate template
+	if err := json.Unmarshal(raw, &parsedTemplate); err != nil {
+		perunCtx.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "offline-validate"}, logger.Field{Key: "template", Value: templatePath})
+		return false
+	}
+
+	spec, err := specfetcher.Load(context.Background(), region, perunCtx.Config.SpecificationURL)
+	if err != nil {
+		perunCtx.Logger.Error(err.Error(), logger.Field{Key: "mode", Value: "offline-validate"}, logger.Field{Key: "region", Value: region})
+		return false
+	}
+
+	knownTypes, _ := (*spec)["ResourceTypes"].(map[string]interface{})
+
+	for name, resource := range parsedTemplate.Resources {
+		if knownTypes != nil {
+			if _, ok := knownTypes[resource.Type]; !ok {
+				perunCtx.Logger.Error(
+					"unknown resource type "+resource.Type+" for resource "+name,
+					logger.Field{Key: "mode", Value: "offline-validate"},
+					logger.Field{Key: "resource", Value: name},
+					logger.Field{Key: "region", Value: region},
+				)
+				return false
+			}
+		}
+	}
+
+	perunCtx.Logger.Always("Template is valid against the cached resource specification.", logger.Field{Key: "mode", Value: "offline-validate"}, logger.Field{Key: "region", Value: region})
+	return true
+}
@@ -0,0 +1,221 @@
+// Copyright 2017 Appliscale
+//
+// Maintainers and contributors are listed in README file inside repository.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package specfetcher resolves and caches the CloudFormation resource
+// specification for a region, so offlinevalidator doesn't depend on a
+// hardcoded, ever-staling map of CloudFront URLs.
+package specfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	perunContext "github.com/Appliscale/perun/context"
+	"github.com/Appliscale/perun/mysession"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const latestSpecLocationParameter = "/aws/service/cloudformation/CloudFormationResourceSpecification/LatestResourceSpecificationLocation"
+
+// Spec is the parsed CloudFormation resource specification for a region.
+// Its shape mirrors the upstream JSON document; offlinevalidator is the
+// only consumer, so we keep it as a raw, untyped document here.
+type Spec map[string]interface{}
+
+// Load resolves the current resource specification URL for region (via
+// SSM, falling back to the built-in map on failure), downloads it if the
+// cache is stale or missing, and returns the parsed specification. If
+// the URL can't be resolved or the download fails (no network, as in an
+// air-gapped environment), it falls back to whatever a prior Update
+// cached for this region.
+func Load(ctx context.Context, region string, fallbackURLs map[string]string) (*Spec, error) {
+	cacheDir, err := cacheDirFor(region)
+	if err != nil {
+		return nil, err
+	}
+
+	etag := cachedETag(cacheDir)
+
+	url := resolveURL(ctx, region, fallbackURLs)
+	if url == "" {
+		if etag != "" {
+			return readCached(cacheDir, etag)
+		}
+		return nil, errors.New("no resource specification URL known for region " + region)
+	}
+
+	body, newETag, err := download(ctx, url, etag)
+	if err != nil {
+		if etag != "" {
+			return readCached(cacheDir, etag)
+		}
+		return nil, err
+	}
+
+	if body == nil {
+		// 304 Not Modified: the cached copy is still current.
+		return readCached(cacheDir, etag)
+	}
+
+	if err := store(cacheDir, newETag, body); err != nil {
+		return nil, err
+	}
+
+	return parse(body)
+}
+
+// resolveURL asks SSM for the current specification location, falling
+// back to perun's built-in map when SSM is unreachable or unset.
+func resolveURL(ctx context.Context, region string, fallbackURLs map[string]string) string {
+	session, err := mysession.GetSession(region)
+	if err == nil {
+		client := ssm.New(session)
+		output, err := client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+			Name: aws.String(latestSpecLocationParameter),
+		})
+		if err == nil && output.Parameter != nil && output.Parameter.Value != nil {
+			return *output.Parameter.Value
+		}
+	}
+
+	return fallbackURLs[region]
+}
+
+func download(ctx context.Context, url, etag string) (body []byte, newETag string, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, "", errors.New("unexpected status fetching resource specification: " + response.Status)
+	}
+
+	body, err = ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, response.Header.Get("ETag"), nil
+}
+
+func parse(body []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func cacheDirFor(region string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "perun", "spec", region), nil
+}
+
+func cachedETag(cacheDir string) string {
+	entries, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return ""
+	}
+
+	var newest os.FileInfo
+	for _, entry := range entries {
+		if newest == nil || entry.ModTime().After(newest.ModTime()) {
+			newest = entry
+		}
+	}
+
+	if newest == nil {
+		return ""
+	}
+
+	return strippedExtension(newest.Name())
+}
+
+func readCached(cacheDir, etag string) (*Spec, error) {
+	body, err := ioutil.ReadFile(filepath.Join(cacheDir, etag+".json"))
+	if err != nil {
+		return nil, err
+	}
+	return parse(body)
+}
+
+func store(cacheDir, etag string, body []byte) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(cacheDir, sanitizeETag(etag)+".json")
+	return ioutil.WriteFile(path, body, 0644)
+}
+
+func strippedExtension(name string) string {
+	return name[:len(name)-len(filepath.Ext(name))]
+}
+
+func sanitizeETag(etag string) string {
+	if etag == "" {
+		return time.Now().UTC().Format("20060102T150405Z")
+	}
+
+	clean := make([]rune, 0, len(etag))
+	for _, r := range etag {
+		if r == '"' || r == '/' {
+			continue
+		}
+		clean = append(clean, r)
+	}
+
+	return string(clean)
+}
+
+// Update pre-warms the on-disk cache for each of the given regions, so
+// air-gapped validation doesn't need a first-run network hit. It stops
+// at the first region that fails.
+func Update(ctx *perunContext.Context, regions []string) error {
+	for _, region := range regions {
+		ctx.Logger.Always("Updating cached resource specification for " + region)
+		if _, err := Load(context.Background(), region, ctx.Config.SpecificationURL); err != nil {
+			ctx.Logger.Error(err.Error())
+			return err
+		}
+	}
+	return nil
+}